@@ -0,0 +1,459 @@
+package fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+var (
+	// errArchiveOpen describes the error when an archive cannot be opened or read.
+	errArchiveOpen = errors.New("open archive")
+	// errArchiveFormat describes the error when the archive format is unknown or unsupported.
+	errArchiveFormat = errors.New("unsupported archive format")
+	// errPathTraversal describes the error when an archive entry tries to escape the target directory.
+	errPathTraversal = errors.New("illegal file path")
+	// errTooManyFiles describes the error when an archive exceeds the configured MaxFiles.
+	errTooManyFiles = errors.New("archive contains too many files")
+	// errArchiveTooLarge describes the error when an archive exceeds the configured MaxSize.
+	errArchiveTooLarge = errors.New("archive exceeds the maximum allowed size")
+)
+
+// ExtractOptions holds the configuration built from the ExtractOption(s)
+// passed to Extract.
+type ExtractOptions struct {
+	// MaxSize is the maximum total number of bytes that will be written to
+	// disk while extracting, used as a zip-bomb guard. Zero means unlimited.
+	MaxSize int64
+	// MaxFiles is the maximum number of entries that will be extracted.
+	// Zero means unlimited.
+	MaxFiles int
+	// Filter, when not nil, is called for every entry name found in the
+	// archive; entries for which it returns false are skipped entirely.
+	Filter func(name string) bool
+}
+
+// ExtractOption sets a field of ExtractOptions.
+type ExtractOption func(*ExtractOptions)
+
+// MaxSize sets the maximum total number of bytes Extract is allowed to
+// write to disk.
+func MaxSize(n int64) ExtractOption {
+	return func(opts *ExtractOptions) {
+		opts.MaxSize = n
+	}
+}
+
+// MaxFiles sets the maximum number of entries Extract is allowed to write.
+func MaxFiles(n int) ExtractOption {
+	return func(opts *ExtractOptions) {
+		opts.MaxFiles = n
+	}
+}
+
+// Filter sets a predicate that decides which entries of the archive are
+// extracted; entry names for which it returns false are skipped.
+func Filter(f func(name string) bool) ExtractOption {
+	return func(opts *ExtractOptions) {
+		opts.Filter = f
+	}
+}
+
+// extractState is carried across entries of a single Extract call so that
+// MaxSize/MaxFiles can be enforced across the whole archive.
+type extractState struct {
+	opts          ExtractOptions
+	createdFolder string
+	filesWritten  int
+	bytesWritten  int64
+}
+
+// safeJoin joins target with the archive-provided name and guarantees that
+// the resulting path is a descendant of target, rejecting absolute paths
+// and any ".." segment that would let the entry escape target.
+func safeJoin(target, name string) (string, error) {
+	if filepath.IsAbs(name) || strings.Contains(filepath.ToSlash(name), "../") || name == ".." {
+		return "", fmt.Errorf("%w: %s", errPathTraversal, name)
+	}
+
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(target, name)
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	if absPath != absTarget && !strings.HasPrefix(absPath, absTarget+PathSeparator) {
+		return "", fmt.Errorf("%w: %s", errPathTraversal, name)
+	}
+
+	return path, nil
+}
+
+// safeSymlink validates that a symlink entry, once resolved relative to its
+// own location, still points inside target, and creates it.
+func safeSymlink(target, path, linkname string) error {
+	if filepath.IsAbs(linkname) {
+		return fmt.Errorf("%w: symlink %s -> %s", errPathTraversal, path, linkname)
+	}
+
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+
+	absResolved, err := filepath.Abs(filepath.Join(filepath.Dir(path), linkname))
+	if err != nil {
+		return err
+	}
+
+	if absResolved != absTarget && !strings.HasPrefix(absResolved, absTarget+PathSeparator) {
+		return fmt.Errorf("%w: symlink %s -> %s", errPathTraversal, path, linkname)
+	}
+
+	os.Remove(path)
+	return os.Symlink(linkname, path)
+}
+
+func (st *extractState) allow(name string) bool {
+	return st.opts.Filter == nil || st.opts.Filter(name)
+}
+
+func (st *extractState) track(size int64) error {
+	st.filesWritten++
+	if st.opts.MaxFiles > 0 && st.filesWritten > st.opts.MaxFiles {
+		return errTooManyFiles
+	}
+
+	st.bytesWritten += size
+	if st.opts.MaxSize > 0 && st.bytesWritten > st.opts.MaxSize {
+		return errArchiveTooLarge
+	}
+
+	return nil
+}
+
+// Extract detects archive's format from its extension (falling back to its
+// magic bytes) and extracts it into target, returning the path of the
+// top-level folder the archive created, if any.
+//
+// It supports .zip, .tar, .tar.gz/.tgz, .tar.bz2/.tbz2, .tar.xz/.txz and
+// .tar.zst/.tzst archives. Every entry is guarded against zip-slip: entries
+// whose cleaned path would escape target are rejected, and so are symlinks
+// whose resolved target falls outside of it.
+func Extract(archive string, target string, opts ...ExtractOption) (string, error) {
+	var cfg ExtractOptions
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return "", fmt.Errorf("%w: %s", errDirCreate, err.Error())
+	}
+
+	st := &extractState{opts: cfg}
+
+	format, err := detectFormat(archive)
+	if err != nil {
+		return "", err
+	}
+
+	if format == formatZip {
+		return st.createdFolder, extractZip(archive, target, st)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", errArchiveOpen, err.Error())
+	}
+	defer f.Close()
+
+	reader, err := decompressReader(f, format)
+	if err != nil {
+		return "", err
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	if err := extractTar(reader, target, st); err != nil {
+		return "", err
+	}
+
+	return st.createdFolder, nil
+}
+
+// Unzip extracts a zipped file to the target location.
+// It returns the path of the created folder (if any) and an error (if any).
+//
+// It's kept for backwards compatibility; new code should prefer Extract,
+// which also understands tar-based archives.
+func Unzip(archive string, target string) (string, error) {
+	return Extract(archive, target)
+}
+
+type archiveFormat int
+
+const (
+	formatZip archiveFormat = iota
+	formatTar
+	formatTarGz
+	formatTarBz2
+	formatTarXz
+	formatTarZst
+)
+
+// detectFormat resolves the archive format from its extension, falling back
+// to sniffing the first bytes of the file when the extension is unknown.
+func detectFormat(archive string) (archiveFormat, error) {
+	lower := strings.ToLower(archive)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return formatZip, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return formatTar, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return formatTarGz, nil
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz2"):
+		return formatTarBz2, nil
+	case strings.HasSuffix(lower, ".tar.xz"), strings.HasSuffix(lower, ".txz"):
+		return formatTarXz, nil
+	case strings.HasSuffix(lower, ".tar.zst"), strings.HasSuffix(lower, ".tzst"):
+		return formatTarZst, nil
+	}
+
+	// tarMagicLen covers the POSIX "ustar" signature at offset 257, the
+	// furthest into the file any of the signatures below need to look.
+	const tarMagicLen = 263
+
+	magic := make([]byte, tarMagicLen)
+	f, err := os.Open(archive)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errArchiveOpen, err.Error())
+	}
+	n, _ := io.ReadFull(f, magic)
+	f.Close()
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")), bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		return formatZip, nil
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return formatTarGz, nil
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return formatTarBz2, nil
+	case bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return formatTarXz, nil
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return formatTarZst, nil
+	case len(magic) >= 263 && bytes.Equal(magic[257:262], []byte("ustar")):
+		// POSIX tar (and the GNU variant, whose magic is "ustar\x00" /
+		// "ustar  \x00" respectively) stores its signature at a fixed
+		// offset rather than the very start of the file.
+		return formatTar, nil
+	}
+
+	return 0, fmt.Errorf("%w: %s", errArchiveFormat, archive)
+}
+
+// decompressReader wraps f with the decompressor matching format, returning
+// a plain tar stream for extractTar to read. format must not be formatZip.
+func decompressReader(f io.Reader, format archiveFormat) (io.Reader, error) {
+	switch format {
+	case formatTar:
+		return f, nil
+	case formatTarGz:
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errArchiveOpen, err.Error())
+		}
+		return gr, nil
+	case formatTarBz2:
+		return bzip2.NewReader(f), nil
+	case formatTarXz:
+		xr, err := xz.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errArchiveOpen, err.Error())
+		}
+		return xr, nil
+	case formatTarZst:
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errArchiveOpen, err.Error())
+		}
+		return zr.IOReadCloser(), nil
+	}
+
+	return nil, fmt.Errorf("%w: format %d", errArchiveFormat, format)
+}
+
+// extractZip extracts a zip archive, applying the same path-traversal,
+// size and file-count guards as extractTar.
+func extractZip(archive string, target string, st *extractState) error {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errArchiveOpen, err.Error())
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if !st.allow(file.Name) {
+			continue
+		}
+
+		path, err := safeJoin(target, file.Name)
+		if err != nil {
+			return err
+		}
+
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, file.Mode()); err != nil {
+				return fmt.Errorf("%w: %s", errDirCreate, err.Error())
+			}
+			if st.createdFolder == "" {
+				st.createdFolder = path
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("%w: %s", errDirCreate, err.Error())
+		}
+
+		if file.Mode()&os.ModeSymlink != 0 {
+			linkname, err := readZipFile(file)
+			if err != nil {
+				return err
+			}
+			if err := safeSymlink(target, path, string(linkname)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := st.track(int64(file.UncompressedSize64)); err != nil {
+			return err
+		}
+
+		if err := extractZipEntry(file, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	fileReader, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errFileOpen, err.Error())
+	}
+	defer fileReader.Close()
+
+	data, err := io.ReadAll(fileReader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errFileRead, err.Error())
+	}
+	return data, nil
+}
+
+func extractZipEntry(file *zip.File, path string) error {
+	fileReader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %s", errFileOpen, err.Error())
+	}
+	defer fileReader.Close()
+
+	targetFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
+	if err != nil {
+		return fmt.Errorf("%w: %s", errFileOpen, err.Error())
+	}
+	defer targetFile.Close()
+
+	if _, err := io.Copy(targetFile, fileReader); err != nil {
+		return fmt.Errorf("%w: %s", errFileCopy, err.Error())
+	}
+
+	return nil
+}
+
+// extractTar reads a plain (already decompressed) tar stream from r and
+// writes its entries under target.
+func extractTar(r io.Reader, target string, st *extractState) error {
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s", errArchiveOpen, err.Error())
+		}
+
+		if !st.allow(header.Name) {
+			continue
+		}
+
+		path, err := safeJoin(target, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, os.FileMode(header.Mode)); err != nil {
+				return fmt.Errorf("%w: %s", errDirCreate, err.Error())
+			}
+			if st.createdFolder == "" {
+				st.createdFolder = path
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("%w: %s", errDirCreate, err.Error())
+			}
+			if err := safeSymlink(target, path, header.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("%w: %s", errDirCreate, err.Error())
+			}
+			if err := st.track(header.Size); err != nil {
+				return err
+			}
+			if err := extractTarEntry(tr, path, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractTarEntry(r io.Reader, path string, mode os.FileMode) error {
+	targetFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errFileOpen, err.Error())
+	}
+	defer targetFile.Close()
+
+	if _, err := io.Copy(targetFile, r); err != nil {
+		return fmt.Errorf("%w: %s", errFileCopy, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+)
+
+func writeZip(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, contents := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTar(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	for name, contents := range entries {
+		hdr := &tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(contents)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTarGz(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, contents := range entries {
+		hdr := &tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(contents)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractZip(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive.zip")
+	writeZip(t, archive, map[string]string{
+		"hello.txt":      "hello world",
+		"sub/nested.txt": "nested contents",
+	})
+
+	target := filepath.Join(dir, "out")
+	if _, err := Extract(archive, target); err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(target, "hello.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello world" {
+		t.Errorf("hello.txt contents = %q, want %q", got, "hello world")
+	}
+
+	got, err = os.ReadFile(filepath.Join(target, "sub", "nested.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "nested contents" {
+		t.Errorf("sub/nested.txt contents = %q, want %q", got, "nested contents")
+	}
+}
+
+func TestExtractTar(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive.tar")
+	writeTar(t, archive, map[string]string{
+		"hello.txt": "hello from tar",
+	})
+
+	target := filepath.Join(dir, "out")
+	if _, err := Extract(archive, target); err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(target, "hello.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello from tar" {
+		t.Errorf("hello.txt contents = %q, want %q", got, "hello from tar")
+	}
+}
+
+func TestExtractRejectsZipSlip(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "evil.zip")
+	writeZip(t, archive, map[string]string{
+		"../../etc/passwd": "pwned",
+	})
+
+	target := filepath.Join(dir, "out")
+	if _, err := Extract(archive, target); !errors.Is(err, errPathTraversal) {
+		t.Fatalf("Extract error = %v, want errPathTraversal", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "etc", "passwd")); err == nil {
+		t.Error("zip-slip entry escaped target directory")
+	}
+}
+
+func TestExtractRejectsSymlinkEscape(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "evil-link.tar")
+
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     "link",
+		Typeflag: tar.TypeSymlink,
+		Linkname: "../../../etc",
+		Mode:     0777,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	target := filepath.Join(dir, "out")
+	if _, err := Extract(archive, target); !errors.Is(err, errPathTraversal) {
+		t.Fatalf("Extract error = %v, want errPathTraversal", err)
+	}
+}
+
+func TestExtractTarGz(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive.tar.gz")
+	writeTarGz(t, archive, map[string]string{
+		"hello.txt": "hello from tar.gz",
+	})
+
+	target := filepath.Join(dir, "out")
+	if _, err := Extract(archive, target); err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(target, "hello.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello from tar.gz" {
+		t.Errorf("hello.txt contents = %q, want %q", got, "hello from tar.gz")
+	}
+}
+
+// TestExtractDetectsFormatByMagicBytes checks that Extract falls back to
+// sniffing the archive's magic bytes when its extension doesn't match any
+// known format, e.g. because it was downloaded to a temp file.
+func TestExtractDetectsFormatByMagicBytes(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive.download")
+	writeZip(t, archive, map[string]string{"hello.txt": "sniffed as a zip"})
+
+	target := filepath.Join(dir, "out")
+	if _, err := Extract(archive, target); err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(target, "hello.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "sniffed as a zip" {
+		t.Errorf("hello.txt contents = %q, want %q", got, "sniffed as a zip")
+	}
+}
+
+// TestExtractMaxFiles checks that MaxFiles aborts extraction once the
+// archive contains more entries than allowed.
+func TestExtractMaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive.zip")
+	writeZip(t, archive, map[string]string{
+		"a.txt": "a",
+		"b.txt": "b",
+		"c.txt": "c",
+	})
+
+	target := filepath.Join(dir, "out")
+	if _, err := Extract(archive, target, MaxFiles(2)); !errors.Is(err, errTooManyFiles) {
+		t.Fatalf("Extract error = %v, want errTooManyFiles", err)
+	}
+}
+
+// TestExtractMaxSize checks that MaxSize aborts extraction once the bytes
+// written exceed the configured limit, guarding against zip bombs.
+func TestExtractMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive.zip")
+	writeZip(t, archive, map[string]string{
+		"big.txt": "0123456789",
+	})
+
+	target := filepath.Join(dir, "out")
+	if _, err := Extract(archive, target, MaxSize(4)); !errors.Is(err, errArchiveTooLarge) {
+		t.Fatalf("Extract error = %v, want errArchiveTooLarge", err)
+	}
+}
+
+func TestUnzipBackwardsCompat(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive.zip")
+	writeZip(t, archive, map[string]string{"hello.txt": "hi"})
+
+	target := filepath.Join(dir, "out")
+	if _, err := Unzip(archive, target); err != nil {
+		t.Fatalf("Unzip returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(target, "hello.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, []byte("hi")) {
+		t.Errorf("hello.txt contents = %q, want %q", got, "hi")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package fs
+
+import iofs "io/fs"
+
+// ownerGroup is a no-op on non-unix platforms: there's no portable
+// syscall.Stat_t to resolve a uid/gid from.
+func ownerGroup(info iofs.FileInfo) (owner string, group string) {
+	return "", ""
+}
+
+// fileOwner is a no-op on non-unix platforms: there's no portable
+// syscall.Stat_t to resolve a uid/gid from.
+func fileOwner(info iofs.FileInfo) (uid int, gid int, ok bool) {
+	return 0, 0, false
+}
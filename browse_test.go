@@ -0,0 +1,203 @@
+package fs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setupBrowseDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("bb"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestBrowseHandlerJSON(t *testing.T) {
+	dir := setupBrowseDir(t)
+
+	h := BrowseHandler(os.DirFS(dir), BrowseOptions{})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/json")
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	if ctype := res.Header().Get("Content-Type"); ctype != "application/json; charset=utf-8" {
+		t.Errorf("handler returned wrong content type: got %v", ctype)
+	}
+
+	var entries []browseEntry
+	if err := json.Unmarshal(res.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	// directories sort first, then "a.txt" before "b.txt".
+	if entries[0].Name != "sub" || !entries[0].IsDir {
+		t.Errorf("entries[0] = %+v, want directory \"sub\" first", entries[0])
+	}
+	if entries[1].Name != "a.txt" || entries[2].Name != "b.txt" {
+		t.Errorf("entries not sorted by name: got %q, %q", entries[1].Name, entries[2].Name)
+	}
+}
+
+func TestBrowseHandlerSortBySizeDesc(t *testing.T) {
+	dir := setupBrowseDir(t)
+
+	h := BrowseHandler(os.DirFS(dir), BrowseOptions{})
+	req := httptest.NewRequest("GET", "/?sort=size&order=desc", nil)
+	req.Header.Set("Accept", "application/json")
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	var entries []browseEntry
+	if err := json.Unmarshal(res.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+
+	// directories still sort first regardless of the requested key.
+	if entries[0].Name != "sub" {
+		t.Fatalf("entries[0].Name = %q, want %q", entries[0].Name, "sub")
+	}
+	if entries[1].Name != "b.txt" || entries[2].Name != "a.txt" {
+		t.Errorf("entries not sorted by size descending: got %q, %q", entries[1].Name, entries[2].Name)
+	}
+}
+
+func TestBrowseHandlerServesFile(t *testing.T) {
+	dir := setupBrowseDir(t)
+
+	h := BrowseHandler(os.DirFS(dir), BrowseOptions{})
+	req := httptest.NewRequest("GET", "/a.txt", nil)
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if body := res.Body.String(); body != "a" {
+		t.Errorf("handler returned wrong contents: got %q", body)
+	}
+}
+
+func TestBrowseHandlerHidePatterns(t *testing.T) {
+	dir := setupBrowseDir(t)
+	if err := os.WriteFile(filepath.Join(dir, ".hidden"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := BrowseHandler(os.DirFS(dir), BrowseOptions{HidePatterns: []string{".*"}})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/json")
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	var entries []browseEntry
+	if err := json.Unmarshal(res.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	for _, e := range entries {
+		if e.Name == ".hidden" {
+			t.Error("HidePatterns did not hide \".hidden\"")
+		}
+	}
+}
+
+func TestBrowseHandlerSymlinkPolicy(t *testing.T) {
+	dir := t.TempDir()
+	secretDir := filepath.Join(dir, "secret")
+	servedDir := filepath.Join(dir, "served")
+	if err := os.Mkdir(secretDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(servedDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(secretDir, "topsecret.txt"), []byte("topsecret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "secret"), filepath.Join(servedDir, "link-to-secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("reject hides the listing entry and blocks direct access", func(t *testing.T) {
+		h := BrowseHandler(os.DirFS(servedDir), BrowseOptions{SymlinkPolicy: SymlinkReject})
+
+		listReq := httptest.NewRequest("GET", "/", nil)
+		listReq.Header.Set("Accept", "application/json")
+		listRes := httptest.NewRecorder()
+		h.ServeHTTP(listRes, listReq)
+
+		var entries []browseEntry
+		if err := json.Unmarshal(listRes.Body.Bytes(), &entries); err != nil {
+			t.Fatalf("response is not valid JSON: %v", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("SymlinkReject listing = %+v, want no entries", entries)
+		}
+
+		fileReq := httptest.NewRequest("GET", "/link-to-secret/topsecret.txt", nil)
+		fileRes := httptest.NewRecorder()
+		h.ServeHTTP(fileRes, fileReq)
+		if fileRes.Code != http.StatusNotFound {
+			t.Errorf("direct request through a rejected symlink returned %d, want %d (leaked: %q)",
+				fileRes.Code, http.StatusNotFound, fileRes.Body.String())
+		}
+	})
+
+	t.Run("show-as-link lists the entry but still blocks direct access", func(t *testing.T) {
+		h := BrowseHandler(os.DirFS(servedDir), BrowseOptions{SymlinkPolicy: SymlinkShowAsLink})
+
+		listReq := httptest.NewRequest("GET", "/", nil)
+		listReq.Header.Set("Accept", "application/json")
+		listRes := httptest.NewRecorder()
+		h.ServeHTTP(listRes, listReq)
+
+		var entries []browseEntry
+		if err := json.Unmarshal(listRes.Body.Bytes(), &entries); err != nil {
+			t.Fatalf("response is not valid JSON: %v", err)
+		}
+		if len(entries) != 1 || !entries[0].IsSymlink {
+			t.Fatalf("SymlinkShowAsLink listing = %+v, want one symlink entry", entries)
+		}
+
+		fileReq := httptest.NewRequest("GET", "/link-to-secret/topsecret.txt", nil)
+		fileRes := httptest.NewRecorder()
+		h.ServeHTTP(fileRes, fileReq)
+		if fileRes.Code != http.StatusNotFound {
+			t.Errorf("direct request through an unfollowed symlink returned %d, want %d (leaked: %q)",
+				fileRes.Code, http.StatusNotFound, fileRes.Body.String())
+		}
+	})
+
+	t.Run("follow resolves the symlink for both listing and direct access", func(t *testing.T) {
+		h := BrowseHandler(os.DirFS(servedDir), BrowseOptions{SymlinkPolicy: SymlinkFollow})
+
+		fileReq := httptest.NewRequest("GET", "/link-to-secret/topsecret.txt", nil)
+		fileRes := httptest.NewRecorder()
+		h.ServeHTTP(fileRes, fileReq)
+		if fileRes.Code != http.StatusOK {
+			t.Fatalf("direct request through a followed symlink returned %d, want %d", fileRes.Code, http.StatusOK)
+		}
+		if body := fileRes.Body.String(); body != "topsecret" {
+			t.Errorf("handler returned wrong contents: got %q", body)
+		}
+	})
+}
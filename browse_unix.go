@@ -0,0 +1,42 @@
+//go:build unix
+
+package fs
+
+import (
+	iofs "io/fs"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// ownerGroup resolves the owner and group names of info from its underlying
+// syscall.Stat_t, falling back to the raw numeric id when the name can't be
+// looked up (e.g. the uid/gid isn't registered on this host).
+func ownerGroup(info iofs.FileInfo) (owner string, group string) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", ""
+	}
+
+	owner = strconv.FormatUint(uint64(stat.Uid), 10)
+	if u, err := user.LookupId(owner); err == nil {
+		owner = u.Username
+	}
+
+	group = strconv.FormatUint(uint64(stat.Gid), 10)
+	if g, err := user.LookupGroupId(group); err == nil {
+		group = g.Name
+	}
+
+	return owner, group
+}
+
+// fileOwner extracts info's numeric uid/gid from its underlying
+// syscall.Stat_t, reporting false when that's not available.
+func fileOwner(info iofs.FileInfo) (uid int, gid int, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
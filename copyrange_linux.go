@@ -0,0 +1,35 @@
+//go:build linux
+
+package fs
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyFileRange copies size bytes from src to dst using the copy_file_range
+// syscall, which lets the kernel copy data between two file descriptors
+// without round-tripping it through userspace. It reports whether the copy
+// fully succeeded; on any error it rewinds both files and leaves dst
+// truncated so the caller can fall back to copyFileData's generic path.
+func copyFileRange(dst, src *os.File, size int64) bool {
+	if size <= 0 {
+		return false
+	}
+
+	remain := size
+	for remain > 0 {
+		n, err := unix.CopyFileRange(int(src.Fd()), nil, int(dst.Fd()), nil, int(remain), 0)
+		if err != nil || n == 0 {
+			dst.Truncate(0)
+			dst.Seek(0, io.SeekStart)
+			src.Seek(0, io.SeekStart)
+			return false
+		}
+		remain -= int64(n)
+	}
+
+	return true
+}
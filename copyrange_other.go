@@ -0,0 +1,12 @@
+//go:build !linux
+
+package fs
+
+import "os"
+
+// copyFileRange is a no-op on platforms without copy_file_range/sendfile
+// support here; the caller always falls back to copyFileData's generic
+// io.CopyBuffer path.
+func copyFileRange(dst, src *os.File, size int64) bool {
+	return false
+}
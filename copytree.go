@@ -0,0 +1,459 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Policy decides what CopyTree does when the destination of an entry
+// already exists.
+type Policy int
+
+const (
+	// OverwriteAlways always copies over an existing destination file. This
+	// is the default, matching the historical behavior of CopyDir/CopyFile.
+	OverwriteAlways Policy = iota
+	// OverwriteSkip leaves an existing destination file untouched.
+	OverwriteSkip
+	// OverwriteIfNewer only copies over an existing destination file when
+	// the source is strictly newer than it.
+	OverwriteIfNewer
+)
+
+// CopyOptions holds the configuration built from the CopyOption(s) passed
+// to CopyTree.
+type CopyOptions struct {
+	// Concurrency is the number of files copied in parallel.
+	// runtime.NumCPU() is used when it's zero.
+	Concurrency int
+	// PreserveTimes restores each copied file's source modification time.
+	PreserveTimes bool
+	// PreserveOwnership restores each copied file's source uid/gid; it has
+	// no effect on platforms without a concept of file ownership.
+	PreserveOwnership bool
+	// FollowSymlinks copies the file/directory a symlink points to instead
+	// of the symlink itself.
+	FollowSymlinks bool
+	// Overwrite decides what to do when the destination of an entry
+	// already exists.
+	Overwrite Policy
+	// Progress, when not nil, is called as each file is copied with the
+	// number of bytes copied so far and the file's total size.
+	Progress func(path string, copied, total int64)
+	// Filter, when not nil, is called for every entry found while walking
+	// src; entries for which it returns false are skipped (directories are
+	// skipped entirely, along with their contents).
+	Filter func(path string, d iofs.DirEntry) bool
+	// FailFast stops the walk as soon as one file fails to copy, instead of
+	// continuing and aggregating every error.
+	FailFast bool
+}
+
+// CopyOption sets a field of CopyOptions.
+type CopyOption func(*CopyOptions)
+
+// Concurrency sets the number of files CopyTree copies in parallel.
+func Concurrency(n int) CopyOption {
+	return func(opts *CopyOptions) {
+		opts.Concurrency = n
+	}
+}
+
+// PreserveTimes makes CopyTree restore each copied file's modification time.
+func PreserveTimes(enable bool) CopyOption {
+	return func(opts *CopyOptions) {
+		opts.PreserveTimes = enable
+	}
+}
+
+// PreserveOwnership makes CopyTree restore each copied file's uid/gid.
+func PreserveOwnership(enable bool) CopyOption {
+	return func(opts *CopyOptions) {
+		opts.PreserveOwnership = enable
+	}
+}
+
+// FollowSymlinks makes CopyTree copy the target of a symlink instead of
+// recreating the symlink itself.
+func FollowSymlinks(enable bool) CopyOption {
+	return func(opts *CopyOptions) {
+		opts.FollowSymlinks = enable
+	}
+}
+
+// Overwrite sets the policy CopyTree applies to entries that already exist
+// at the destination.
+func Overwrite(policy Policy) CopyOption {
+	return func(opts *CopyOptions) {
+		opts.Overwrite = policy
+	}
+}
+
+// CopyProgress sets the callback CopyTree reports its per-file progress to.
+func CopyProgress(f func(path string, copied, total int64)) CopyOption {
+	return func(opts *CopyOptions) {
+		opts.Progress = f
+	}
+}
+
+// CopyFilter sets the predicate CopyTree uses to decide which entries of
+// src are copied.
+func CopyFilter(f func(path string, d iofs.DirEntry) bool) CopyOption {
+	return func(opts *CopyOptions) {
+		opts.Filter = f
+	}
+}
+
+// FailFast makes CopyTree abort as soon as one file fails to copy.
+func FailFast(enable bool) CopyOption {
+	return func(opts *CopyOptions) {
+		opts.FailFast = enable
+	}
+}
+
+// copyBufferPool holds reusable 1 MiB buffers for the io.CopyBuffer
+// fallback path, so concurrent copies don't each allocate their own.
+var copyBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 1<<20)
+		return &buf
+	},
+}
+
+// CopyTree recursively copies the directory tree rooted at src into dst,
+// dispatching file copies to a worker pool. Unlike CopyDir, it can preserve
+// symlinks, ownership and modification times, skip or filter entries, and
+// reports errors from individual files by aggregating them with
+// errors.Join instead of aborting the whole walk, unless opts.FailFast is
+// set.
+func CopyTree(src string, dst string, opts ...CopyOption) error {
+	cfg := CopyOptions{Concurrency: runtime.NumCPU()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !srcInfo.IsDir() {
+		return fmt.Errorf("%s: %w", src, errNotDir)
+	}
+
+	type copyJob struct {
+		src string
+		dst string
+	}
+
+	jobs := make(chan copyJob)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		errs    []error
+		stopped sync.Once
+	)
+
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+		if cfg.FailFast {
+			stopped.Do(cancel)
+		}
+	}
+
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if err := copyTreeFile(job.src, job.dst, cfg); err != nil {
+					fail(fmt.Errorf("%s: %w", job.src, err))
+				}
+			}
+		}()
+	}
+
+	// walkTree walks root (whose own mode is rootMode) and mirrors it under
+	// targetRoot. It's used both for src itself and, when FollowSymlinks is
+	// set, for every symlinked directory found along the way: WalkDir never
+	// descends into those on its own, so such a directory is walked again
+	// here with root set to its resolved target.
+	var walkTree func(root, targetRoot string, rootMode os.FileMode) error
+	walkTree = func(root, targetRoot string, rootMode os.FileMode) error {
+		return filepath.WalkDir(root, func(path string, d iofs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+
+			if cfg.Filter != nil && !cfg.Filter(path, d) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			targetPath := filepath.Join(targetRoot, rel)
+
+			if d.Type()&os.ModeSymlink != 0 {
+				if !cfg.FollowSymlinks {
+					if rel == "." {
+						return nil
+					}
+					if err := copySymlink(path, targetPath, cfg); err != nil {
+						fail(fmt.Errorf("%s: %w", path, err))
+					}
+					return nil
+				}
+
+				resolved, err := filepath.EvalSymlinks(path)
+				if err != nil {
+					fail(fmt.Errorf("%s: %w", path, err))
+					return nil
+				}
+				resolvedInfo, err := os.Stat(resolved)
+				if err != nil {
+					fail(fmt.Errorf("%s: %w", path, err))
+					return nil
+				}
+				if resolvedInfo.IsDir() {
+					if err := os.MkdirAll(targetPath, resolvedInfo.Mode()); err != nil {
+						fail(fmt.Errorf("%s: %w", path, fmt.Errorf("%w: %s", errDirCreate, err.Error())))
+						return nil
+					}
+					if err := walkTree(resolved, targetPath, resolvedInfo.Mode()); err != nil {
+						fail(fmt.Errorf("%s: %w", path, err))
+					}
+					return nil
+				}
+				// Symlink to a regular file: os.Open follows it transparently,
+				// so fall through to the normal file-copy job below.
+			}
+
+			if d.IsDir() {
+				mode := rootMode
+				if rel != "." {
+					info, err := d.Info()
+					if err != nil {
+						return err
+					}
+					mode = info.Mode()
+				}
+				if err := os.MkdirAll(targetPath, mode); err != nil {
+					return fmt.Errorf("%w: %s", errDirCreate, err.Error())
+				}
+				return nil
+			}
+
+			select {
+			case jobs <- copyJob{src: path, dst: targetPath}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		})
+	}
+
+	walkErr := walkTree(src, dst, srcInfo.Mode())
+
+	close(jobs)
+	wg.Wait()
+
+	if walkErr != nil && !errors.Is(walkErr, context.Canceled) {
+		errs = append(errs, walkErr)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// CopyDir recursively copies a directory tree, attempting to preserve
+// permissions. Source directory must exist.
+//
+// It's kept for backwards compatibility; new code should prefer CopyTree,
+// which copies concurrently and can also preserve symlinks, ownership and
+// modification times.
+func CopyDir(source string, dest string) error {
+	return CopyTree(source, dest)
+}
+
+// copyTreeFile copies the single regular file src to dst, honoring cfg's
+// overwrite policy, progress callback and preservation options.
+func copyTreeFile(src, dst string, cfg CopyOptions) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errFileRead, err.Error())
+	}
+
+	if shouldSkip(dst, srcInfo, cfg.Overwrite) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("%w: %s", errDirCreate, err.Error())
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errFileOpen, err.Error())
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, srcInfo.Mode())
+	if err != nil {
+		return fmt.Errorf("%w: %s", errFileCreate, err.Error())
+	}
+	defer out.Close()
+
+	if err := copyFileData(in, out, srcInfo.Size(), dst, cfg); err != nil {
+		return fmt.Errorf("%w: %s", errFileCopy, err.Error())
+	}
+
+	if err := out.Sync(); err != nil {
+		return fmt.Errorf("%w: %s", errFileCopy, err.Error())
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("%w: %s", errFileCopy, err.Error())
+	}
+
+	if cfg.PreserveOwnership {
+		if err := preserveOwnership(dst, srcInfo); err != nil {
+			return fmt.Errorf("%w: %s", errFileCopy, err.Error())
+		}
+	}
+
+	if cfg.PreserveTimes {
+		if err := os.Chtimes(dst, time.Now(), srcInfo.ModTime()); err != nil {
+			return fmt.Errorf("%w: %s", errFileCopy, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// copyFileData copies size bytes from in to out, trying the platform's
+// zero-copy syscall first (copyFileRange) and falling back to
+// io.CopyBuffer with a pooled 1 MiB buffer, reporting progress as it goes.
+func copyFileData(in, out *os.File, size int64, path string, cfg CopyOptions) error {
+	if copyFileRange(out, in, size) {
+		if cfg.Progress != nil {
+			cfg.Progress(path, size, size)
+		}
+		return nil
+	}
+
+	var w io.Writer = out
+	if cfg.Progress != nil {
+		w = &progressWriter{w: out, path: path, total: size, progress: cfg.Progress}
+	}
+
+	bufp := copyBufferPool.Get().(*[]byte)
+	defer copyBufferPool.Put(bufp)
+
+	_, err := io.CopyBuffer(w, in, *bufp)
+	return err
+}
+
+// progressWriter wraps an io.Writer, reporting the running total through
+// progress after every chunk written.
+type progressWriter struct {
+	w        io.Writer
+	path     string
+	copied   int64
+	total    int64
+	progress func(path string, copied, total int64)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	if n > 0 {
+		pw.copied += int64(n)
+		pw.progress(pw.path, pw.copied, pw.total)
+	}
+	return n, err
+}
+
+// shouldSkip reports whether dst should be left alone given srcInfo and
+// the configured overwrite policy.
+func shouldSkip(dst string, srcInfo os.FileInfo, policy Policy) bool {
+	if policy == OverwriteAlways {
+		return false
+	}
+
+	dstInfo, err := os.Stat(dst)
+	if err != nil {
+		return false
+	}
+
+	switch policy {
+	case OverwriteSkip:
+		return true
+	case OverwriteIfNewer:
+		return !srcInfo.ModTime().After(dstInfo.ModTime())
+	default:
+		return false
+	}
+}
+
+// copySymlink recreates the symlink at src under dst, honoring cfg's
+// overwrite policy.
+func copySymlink(src, dst string, cfg CopyOptions) error {
+	if srcInfo, err := os.Lstat(src); err == nil {
+		if shouldSkip(dst, srcInfo, cfg.Overwrite) {
+			return nil
+		}
+	}
+
+	target, err := os.Readlink(src)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errFileRead, err.Error())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("%w: %s", errDirCreate, err.Error())
+	}
+
+	os.Remove(dst)
+	if err := os.Symlink(target, dst); err != nil {
+		return fmt.Errorf("%w: %s", errFileCreate, err.Error())
+	}
+
+	return nil
+}
+
+// preserveOwnership applies info's uid/gid to dst; it's a no-op on
+// platforms without a concept of file ownership.
+func preserveOwnership(dst string, info os.FileInfo) error {
+	uid, gid, ok := fileOwner(info)
+	if !ok {
+		return nil
+	}
+	return os.Chown(dst, uid, gid)
+}
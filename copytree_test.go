@@ -0,0 +1,376 @@
+package fs
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, path string, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyTreeHappyPath(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	writeTestFile(t, filepath.Join(src, "a.txt"), "a")
+	writeTestFile(t, filepath.Join(src, "sub", "b.txt"), "b")
+
+	if err := CopyTree(src, dst); err != nil {
+		t.Fatalf("CopyTree returned error: %v", err)
+	}
+
+	for name, want := range map[string]string{"a.txt": "a", filepath.Join("sub", "b.txt"): "b"} {
+		got, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatalf("reading %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s contents = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCopyTreeConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	const numFiles = 50
+	for i := 0; i < numFiles; i++ {
+		writeTestFile(t, filepath.Join(src, fmt.Sprintf("dir%d", i), "file.txt"), "data")
+	}
+
+	if err := CopyTree(src, dst, Concurrency(8)); err != nil {
+		t.Fatalf("CopyTree returned error: %v", err)
+	}
+
+	var count int
+	filepath.WalkDir(dst, func(path string, d iofs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			count++
+		}
+		return nil
+	})
+	if count != numFiles {
+		t.Errorf("copied %d files, want %d", count, numFiles)
+	}
+}
+
+func TestCopyTreePreserveTimes(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	writeTestFile(t, filepath.Join(src, "a.txt"), "a")
+	mtime := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(filepath.Join(src, "a.txt"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyTree(src, dst, PreserveTimes(true)); err != nil {
+		t.Fatalf("CopyTree returned error: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dst, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("dst mtime = %v, want %v", info.ModTime(), mtime)
+	}
+}
+
+func TestCopyTreeOverwriteSkip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	writeTestFile(t, filepath.Join(src, "a.txt"), "new")
+	writeTestFile(t, filepath.Join(dst, "a.txt"), "old")
+
+	if err := CopyTree(src, dst, Overwrite(OverwriteSkip)); err != nil {
+		t.Fatalf("CopyTree returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dst, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "old" {
+		t.Errorf("a.txt contents = %q, want %q (OverwriteSkip should have left it alone)", got, "old")
+	}
+}
+
+func TestCopyTreeOverwriteIfNewer(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	writeTestFile(t, filepath.Join(src, "older.txt"), "new contents")
+	writeTestFile(t, filepath.Join(dst, "older.txt"), "kept contents")
+	writeTestFile(t, filepath.Join(src, "newer.txt"), "new contents")
+	writeTestFile(t, filepath.Join(dst, "newer.txt"), "stale contents")
+
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(dst, "older.txt"), now, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(src, "older.txt"), now.Add(-time.Hour), now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dst, "newer.txt"), now.Add(-time.Hour), now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(src, "newer.txt"), now, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyTree(src, dst, Overwrite(OverwriteIfNewer)); err != nil {
+		t.Fatalf("CopyTree returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dst, "older.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "kept contents" {
+		t.Errorf("older.txt contents = %q, want %q (src is not newer, so it should have been left alone)", got, "kept contents")
+	}
+
+	got, err = os.ReadFile(filepath.Join(dst, "newer.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "new contents" {
+		t.Errorf("newer.txt contents = %q, want %q (src is newer, so it should have been overwritten)", got, "new contents")
+	}
+}
+
+func TestCopyTreePreserveOwnership(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	writeTestFile(t, filepath.Join(src, "a.txt"), "a")
+
+	if err := CopyTree(src, dst, PreserveOwnership(true)); err != nil {
+		t.Fatalf("CopyTree returned error: %v", err)
+	}
+
+	srcInfo, err := os.Stat(filepath.Join(src, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstInfo, err := os.Stat(filepath.Join(dst, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcUID, srcGID, ok := fileOwner(srcInfo)
+	if !ok {
+		t.Skip("fileOwner is a no-op on this platform")
+	}
+	dstUID, dstGID, _ := fileOwner(dstInfo)
+	if dstUID != srcUID || dstGID != srcGID {
+		t.Errorf("dst owner = %d:%d, want %d:%d", dstUID, dstGID, srcUID, srcGID)
+	}
+}
+
+func TestCopyTreeJoinsMultipleErrors(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	// Pre-create the destinations as directories, so copyTreeFile's
+	// os.OpenFile for each source regular file fails for every one of
+	// them, regardless of privileges.
+	writeTestFile(t, filepath.Join(src, "bad1.txt"), "data")
+	writeTestFile(t, filepath.Join(src, "bad2.txt"), "data")
+	if err := os.MkdirAll(filepath.Join(dst, "bad1.txt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dst, "bad2.txt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := CopyTree(src, dst)
+	if err == nil {
+		t.Fatal("CopyTree returned no error, want errors from both pre-created directories")
+	}
+
+	msg := err.Error()
+	if !strings.Contains(msg, "bad1.txt") || !strings.Contains(msg, "bad2.txt") {
+		t.Errorf("joined error = %q, want it to mention both bad1.txt and bad2.txt", msg)
+	}
+}
+
+func TestCopyTreeFailFastStopsAfterFirstError(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	const numBad = 5
+	for i := 0; i < numBad; i++ {
+		name := fmt.Sprintf("bad%d.txt", i)
+		writeTestFile(t, filepath.Join(src, name), "data")
+		if err := os.MkdirAll(filepath.Join(dst, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := CopyTree(src, dst, Concurrency(1), FailFast(true))
+	if err == nil {
+		t.Fatal("CopyTree returned no error, want one from the pre-created directories")
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("CopyTree error is not a joined error: %v", err)
+	}
+	if n := len(joined.Unwrap()); n != 1 {
+		t.Errorf("FailFast produced %d errors, want exactly 1 (it should stop after the first)", n)
+	}
+}
+
+func TestCopyTreeFilter(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	writeTestFile(t, filepath.Join(src, "keep.txt"), "keep")
+	writeTestFile(t, filepath.Join(src, ".git", "config"), "ignored")
+
+	err := CopyTree(src, dst, CopyFilter(func(path string, d iofs.DirEntry) bool {
+		return d.Name() != ".git"
+	}))
+	if err != nil {
+		t.Fatalf("CopyTree returned error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dst, "keep.txt")); err != nil {
+		t.Errorf("keep.txt was not copied: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, ".git")); !os.IsNotExist(err) {
+		t.Error("CopyFilter did not exclude .git")
+	}
+}
+
+func TestCopyTreeProgress(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	writeTestFile(t, filepath.Join(src, "a.txt"), "hello world")
+
+	var lastPath string
+	var lastCopied, lastTotal int64
+	err := CopyTree(src, dst, CopyProgress(func(path string, copied, total int64) {
+		lastPath, lastCopied, lastTotal = path, copied, total
+	}))
+	if err != nil {
+		t.Fatalf("CopyTree returned error: %v", err)
+	}
+
+	if lastPath != filepath.Join(dst, "a.txt") {
+		t.Errorf("Progress path = %q, want %q", lastPath, filepath.Join(dst, "a.txt"))
+	}
+	if lastCopied != int64(len("hello world")) || lastTotal != int64(len("hello world")) {
+		t.Errorf("Progress(copied=%d, total=%d), want both %d", lastCopied, lastTotal, len("hello world"))
+	}
+}
+
+func TestCopyTreeFollowsSymlinkedDirectory(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	writeTestFile(t, filepath.Join(src, "real", "a.txt"), "a")
+	writeTestFile(t, filepath.Join(src, "real", "inner", "b.txt"), "b")
+	if err := os.Symlink(filepath.Join(src, "real"), filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyTree(src, dst, FollowSymlinks(true)); err != nil {
+		t.Fatalf("CopyTree returned error: %v", err)
+	}
+
+	info, err := os.Lstat(filepath.Join(dst, "link"))
+	if err != nil {
+		t.Fatalf("dst/link missing: %v", err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf("dst/link is still a symlink, want a real directory")
+	}
+	if !info.IsDir() {
+		t.Fatalf("dst/link is not a directory (mode %v) — a corrupted placeholder file was left instead", info.Mode())
+	}
+
+	for name, want := range map[string]string{
+		"a.txt":                         "a",
+		filepath.Join("inner", "b.txt"): "b",
+	} {
+		got, err := os.ReadFile(filepath.Join(dst, "link", name))
+		if err != nil {
+			t.Fatalf("reading dst/link/%s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("dst/link/%s contents = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCopyTreeKeepsSymlinkWhenNotFollowing(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	writeTestFile(t, filepath.Join(src, "real.txt"), "a")
+	if err := os.Symlink("real.txt", filepath.Join(src, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyTree(src, dst); err != nil {
+		t.Fatalf("CopyTree returned error: %v", err)
+	}
+
+	info, err := os.Lstat(filepath.Join(dst, "link.txt"))
+	if err != nil {
+		t.Fatalf("dst/link.txt missing: %v", err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Error("dst/link.txt is not a symlink, want it preserved as one")
+	}
+}
+
+func TestCopyDirBackwardsCompat(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	writeTestFile(t, filepath.Join(src, "a.txt"), "a")
+
+	if err := CopyDir(src, dst); err != nil {
+		t.Fatalf("CopyDir returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dst, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "a" {
+		t.Errorf("a.txt contents = %q, want %q", got, "a")
+	}
+}
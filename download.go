@@ -0,0 +1,246 @@
+package fs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var (
+	// errDownload describes the error when fetching a remote file fails.
+	errDownload = errors.New("download file")
+	// errChecksumMismatch describes the error when a downloaded file's
+	// sha256 digest doesn't match the expected one.
+	errChecksumMismatch = errors.New("checksum mismatch")
+)
+
+const (
+	partialSuffix     = ".partial"
+	partialHashSuffix = ".sha256state"
+)
+
+// DownloadOptions holds the configuration built from the DownloadOption(s)
+// passed to Download.
+type DownloadOptions struct {
+	// ExpectedSHA256, when set, is compared (case-insensitively) against the
+	// downloaded file's digest; a mismatch is reported as errChecksumMismatch
+	// and dest is left untouched.
+	ExpectedSHA256 string
+	// Progress, when not nil, is called after every chunk is written with
+	// the number of bytes written so far and the total size reported by the
+	// server, or 0 if unknown.
+	Progress func(bytesDone, bytesTotal int64)
+	// Client is the http.Client used to perform the request. http.DefaultClient is used when nil.
+	Client *http.Client
+}
+
+// DownloadOption sets a field of DownloadOptions.
+type DownloadOption func(*DownloadOptions)
+
+// ExpectedSHA256 makes Download fail with errChecksumMismatch if the
+// downloaded content doesn't hash to sum (a hex-encoded sha256 digest).
+func ExpectedSHA256(sum string) DownloadOption {
+	return func(opts *DownloadOptions) {
+		opts.ExpectedSHA256 = sum
+	}
+}
+
+// DownloadProgress sets the callback Download reports its progress to.
+func DownloadProgress(f func(bytesDone, bytesTotal int64)) DownloadOption {
+	return func(opts *DownloadOptions) {
+		opts.Progress = f
+	}
+}
+
+// DownloadClient sets the http.Client Download performs the request with.
+func DownloadClient(client *http.Client) DownloadOption {
+	return func(opts *DownloadOptions) {
+		opts.Client = client
+	}
+}
+
+// Download fetches url and streams it to dest, writing through a .partial
+// sibling file and renaming it to dest only once the transfer is complete
+// (and, if ExpectedSHA256 was given, verified). If a .partial file from a
+// previous, interrupted attempt is found, Download resumes it with a Range
+// request, picking the sha256 state back up from a .sha256state sidecar.
+func Download(ctx context.Context, url string, dest string, opts ...DownloadOption) error {
+	var cfg DownloadOptions
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	partialPath := dest + partialSuffix
+	hashStatePath := partialPath + partialHashSuffix
+
+	h := sha256.New()
+	var offset int64
+	if fi, err := os.Stat(partialPath); err == nil {
+		if loadHashState(hashStatePath, h) {
+			offset = fi.Size()
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errDownload, err.Error())
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errDownload, err.Error())
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// server ignored our Range request (or there was nothing to resume): start over.
+		offset = 0
+		h = sha256.New()
+		flags |= os.O_TRUNC
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	default:
+		return fmt.Errorf("%w: %s: unexpected status %s", errDownload, url, resp.Status)
+	}
+
+	total := resp.ContentLength
+	if total < 0 {
+		total = 0
+	} else {
+		total += offset
+	}
+
+	partial, err := os.OpenFile(partialPath, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errFileCreate, err.Error())
+	}
+	defer partial.Close()
+
+	cw := &checksumWriter{
+		w:         partial,
+		h:         h,
+		done:      offset,
+		total:     total,
+		progress:  cfg.Progress,
+		statePath: hashStatePath,
+	}
+
+	if _, err := io.Copy(cw, resp.Body); err != nil {
+		saveHashState(hashStatePath, h)
+		return fmt.Errorf("%w: %s", errDownload, err.Error())
+	}
+
+	if err := partial.Sync(); err != nil {
+		return fmt.Errorf("%w: %s", errDownload, err.Error())
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if cfg.ExpectedSHA256 != "" && !strings.EqualFold(sum, cfg.ExpectedSHA256) {
+		return fmt.Errorf("%w: %s: got %s, want %s", errChecksumMismatch, url, sum, cfg.ExpectedSHA256)
+	}
+
+	if err := partial.Close(); err != nil {
+		return fmt.Errorf("%w: %s", errDownload, err.Error())
+	}
+
+	if err := os.Rename(partialPath, dest); err != nil {
+		return fmt.Errorf("%w: %s", errDownload, err.Error())
+	}
+
+	os.Remove(hashStatePath)
+	return nil
+}
+
+// DownloadAndExtract downloads url to a temporary file and extracts it into
+// target, removing the temporary file afterwards. It returns the path of
+// the top-level folder the archive created, if any.
+func DownloadAndExtract(ctx context.Context, url string, target string, opts ...DownloadOption) (string, error) {
+	tmp, err := os.CreateTemp("", "go-fs-download-*")
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", errFileCreate, err.Error())
+	}
+	dest := tmp.Name()
+	tmp.Close()
+	os.Remove(dest)
+	defer os.Remove(dest)
+
+	if err := Download(ctx, url, dest, opts...); err != nil {
+		return "", err
+	}
+
+	return Extract(dest, target)
+}
+
+// checksumWriter writes through to w while feeding every chunk to h and
+// reporting progress, checkpointing the hash state after each chunk so an
+// interrupted download can resume without rehashing bytes it already wrote.
+type checksumWriter struct {
+	w         io.Writer
+	h         hash.Hash
+	done      int64
+	total     int64
+	progress  func(bytesDone, bytesTotal int64)
+	statePath string
+}
+
+func (cw *checksumWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	if n > 0 {
+		cw.h.Write(p[:n])
+		cw.done += int64(n)
+		saveHashState(cw.statePath, cw.h)
+		if cw.progress != nil {
+			cw.progress(cw.done, cw.total)
+		}
+	}
+	return n, err
+}
+
+func saveHashState(path string, h hash.Hash) {
+	marshaler, ok := h.(encoding.BinaryMarshaler)
+	if !ok {
+		return
+	}
+	state, err := marshaler.MarshalBinary()
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(path, state, 0600)
+}
+
+// loadHashState restores h from the sidecar file at path, returning true on
+// success. On any failure h is reset so the caller can fall back to
+// restarting the download from scratch.
+func loadHashState(path string, h hash.Hash) bool {
+	state, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	unmarshaler, ok := h.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return false
+	}
+	if err := unmarshaler.UnmarshalBinary(state); err != nil {
+		h.Reset()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,217 @@
+package fs
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownload(t *testing.T) {
+	const body = "the quick brown fox jumps over the lazy dog"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		http.ServeContent(res, req, "file", time.Time{}, strings.NewReader(body))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "download.txt")
+
+	if err := Download(context.Background(), srv.URL, dest); err != nil {
+		t.Fatalf("Download returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != body {
+		t.Errorf("downloaded contents = %q, want %q", got, body)
+	}
+
+	if _, err := os.Stat(dest + partialSuffix); !os.IsNotExist(err) {
+		t.Error("Download left a .partial file behind")
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	const body = "mismatched contents"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		http.ServeContent(res, req, "file", time.Time{}, strings.NewReader(body))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "download.txt")
+
+	err := Download(context.Background(), srv.URL, dest, ExpectedSHA256("0000000000000000000000000000000000000000000000000000000000000"))
+	if !errors.Is(err, errChecksumMismatch) {
+		t.Fatalf("Download error = %v, want errChecksumMismatch", err)
+	}
+
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("Download renamed dest despite a checksum mismatch")
+	}
+}
+
+func TestDownloadExpectedSHA256Matches(t *testing.T) {
+	const body = "checksum me"
+	sum := sha256.Sum256([]byte(body))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		http.ServeContent(res, req, "file", time.Time{}, strings.NewReader(body))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "download.txt")
+
+	if err := Download(context.Background(), srv.URL, dest, ExpectedSHA256(hex.EncodeToString(sum[:]))); err != nil {
+		t.Fatalf("Download returned error: %v", err)
+	}
+}
+
+func TestDownloadResumesFromPartial(t *testing.T) {
+	const body = "0123456789abcdefghijklmnopqrstuvwxyz"
+	const splitAt = 10
+
+	var gotRange string
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		gotRange = req.Header.Get("Range")
+		http.ServeContent(res, req, "file", time.Time{}, strings.NewReader(body))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "download.txt")
+	partialPath := dest + partialSuffix
+	hashStatePath := partialPath + partialHashSuffix
+
+	if err := os.WriteFile(partialPath, []byte(body[:splitAt]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(body[:splitAt]))
+	saveHashState(hashStatePath, h)
+
+	if err := Download(context.Background(), srv.URL, dest); err != nil {
+		t.Fatalf("Download returned error: %v", err)
+	}
+
+	if gotRange == "" {
+		t.Error("Download did not send a Range request to resume the partial file")
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != body {
+		t.Errorf("resumed download contents = %q, want %q", got, body)
+	}
+}
+
+func TestDownloadProgressUnknownLength(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "text/plain")
+		res.(http.Flusher).Flush() // force chunked transfer: no Content-Length
+		res.Write([]byte("streamed without a known length"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "download.txt")
+
+	var lastTotal int64 = -1
+	err := Download(context.Background(), srv.URL, dest, DownloadProgress(func(done, total int64) {
+		lastTotal = total
+	}))
+	if err != nil {
+		t.Fatalf("Download returned error: %v", err)
+	}
+
+	if lastTotal != 0 {
+		t.Errorf("Progress total = %d for an unknown Content-Length, want 0", lastTotal)
+	}
+}
+
+// TestDownloadAndExtractBareTar checks that a plain (uncompressed) tar
+// served with no extension and no Content-Type hint is still recognized
+// and extracted, relying on detectFormat's ustar magic-byte fallback.
+func TestDownloadAndExtractBareTar(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	const contents = "hello from a bare tar"
+	if err := tw.WriteHeader(&tar.Header{Name: "hello.txt", Mode: 0644, Size: int64(len(contents))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(contents)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		// Deliberately no extension in the URL and no Content-Type, so
+		// DownloadAndExtract must fall back to sniffing the tar's magic.
+		res.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+
+	if _, err := DownloadAndExtract(context.Background(), srv.URL, dir); err != nil {
+		t.Fatalf("DownloadAndExtract returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "hello.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != contents {
+		t.Errorf("extracted hello.txt = %q, want %q", got, contents)
+	}
+}
+
+// TestDownloadAndExtractZip checks the common case, where the URL's
+// extension is enough to pick the archive format.
+func TestDownloadAndExtractZip(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "archive.zip")
+	writeZip(t, zipPath, map[string]string{"hello.txt": "hello from a zip"})
+	zipBytes, err := os.ReadFile(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		http.ServeContent(res, req, "archive.zip", time.Time{}, bytes.NewReader(zipBytes))
+	}))
+	defer srv.Close()
+
+	dest := t.TempDir()
+	if _, err := DownloadAndExtract(context.Background(), srv.URL+"/archive.zip", dest); err != nil {
+		t.Fatalf("DownloadAndExtract returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dest, "hello.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello from a zip" {
+		t.Errorf("extracted hello.txt = %q, want %q", got, "hello from a zip")
+	}
+}
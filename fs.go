@@ -2,7 +2,6 @@
 package fs
 
 import (
-	"archive/zip"
 	"errors"
 	"fmt"
 	"io"
@@ -116,95 +115,6 @@ func CopyFile(source string, destination string) error {
 	return nil
 }
 
-// CopyDir recursively copies a directory tree, attempting to preserve permissions.
-// Source directory must exist.
-func CopyDir(source string, dest string) (err error) {
-
-	// get properties of source dir
-	fi, err := os.Stat(source)
-	if err != nil {
-		return err
-	}
-
-	if !fi.IsDir() {
-		return fmt.Errorf("%s: %w", source, errNotDir)
-	}
-
-	// create dest dir
-
-	err = os.MkdirAll(dest, fi.Mode())
-	if err != nil {
-		return err
-	}
-
-	entries, err := os.ReadDir(source)
-
-	for _, entry := range entries {
-
-		sfp := source + PathSeparator + entry.Name()
-		dfp := dest + PathSeparator + entry.Name()
-		if entry.IsDir() {
-			err = CopyDir(sfp, dfp)
-			if err != nil {
-				return
-			}
-		} else {
-			// perform copy
-			err = CopyFile(sfp, dfp)
-			if err != nil {
-				return
-			}
-		}
-
-	}
-	return
-}
-
-// Unzip extracts a zipped file to the target location
-// returns the path of the created folder (if any) and an error (if any)
-func Unzip(archive string, target string) (string, error) {
-	reader, err := zip.OpenReader(archive)
-	if err != nil {
-		return "", err
-	}
-
-	if err := os.MkdirAll(target, 0755); err != nil {
-		return "", fmt.Errorf("%w: %s", errDirCreate, err.Error())
-	}
-	createdFolder := ""
-	for _, file := range reader.File {
-		path := filepath.Join(target, file.Name)
-		if file.FileInfo().IsDir() {
-			os.MkdirAll(path, file.Mode())
-			if createdFolder == "" {
-				// this is the new directory that zip has
-				createdFolder = path
-			}
-			continue
-		}
-
-		fileReader, err := file.Open()
-		if err != nil {
-			return "", fmt.Errorf("%w: %s", errFileOpen, err.Error())
-		}
-		defer fileReader.Close()
-
-		targetFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
-		if err != nil {
-			return "", fmt.Errorf("%w: %s", errFileOpen, err.Error())
-		}
-		defer targetFile.Close()
-
-		if _, err := io.Copy(targetFile, fileReader); err != nil {
-			return "", fmt.Errorf("%w: %s", errFileCopy, err.Error())
-		}
-
-	}
-
-	reader.Close()
-	return createdFolder, nil
-}
-
 // TypeByExtension returns the MIME type associated with the file extension ext.
 // The extension ext should begin with a leading dot, as in ".html".
 // When ext has no associated type, TypeByExtension returns "".
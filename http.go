@@ -0,0 +1,187 @@
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	iofs "io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// contentDisposition is the header key used to instruct the browser to
+// download a file instead of rendering it inline.
+const contentDisposition = "Content-Disposition"
+
+// staticContentType resolves the Content-Type that should be sent for name,
+// preferring the extension-based lookup and falling back to cType when the
+// extension is unknown or missing. The returned type always carries a
+// charset parameter so that browsers don't have to guess the encoding.
+func staticContentType(name string, cType string) string {
+	t := mime.TypeByExtension(filepath.Ext(name))
+	if t == "" {
+		if cType != "" {
+			t = cType
+		} else {
+			t = "application/octet-stream"
+		}
+	}
+
+	if !strings.Contains(t, "charset") {
+		t += "; charset=utf-8"
+	}
+
+	return t
+}
+
+// staticOptions holds the configuration built from the StaticOption(s)
+// passed to StaticContentHandler/SendStaticFileHandler/SendStaticFS.
+type staticOptions struct {
+	DetectContentType bool
+}
+
+// StaticOption sets a field of staticOptions.
+type StaticOption func(*staticOptions)
+
+// DetectContentType makes the handler sniff the served content's bytes to
+// resolve its Content-Type via TypeByContent, instead of trusting the
+// request's path extension. Useful for extensionless or misleadingly named
+// files.
+func DetectContentType(enable bool) StaticOption {
+	return func(opts *staticOptions) {
+		opts.DetectContentType = enable
+	}
+}
+
+// OpenFunc opens name from fsys; it's the signature a middleware installed
+// via WithOpenHook must implement.
+type OpenFunc func(fsys iofs.FS, name string) (iofs.File, error)
+
+// hookedFS wraps an fs.FS, routing every Open call through a hook so
+// callers can inject authorization or logging at the FS layer.
+type hookedFS struct {
+	fsys iofs.FS
+	hook OpenFunc
+}
+
+func (h *hookedFS) Open(name string) (iofs.File, error) {
+	return h.hook(h.fsys, name)
+}
+
+// WithOpenHook wraps fsys so that every Open performed against it, directly
+// or through FS/FaviconFS/SendStaticFS, goes through hook first. hook is
+// responsible for calling fsys.Open itself if it lets the request through.
+func WithOpenHook(fsys iofs.FS, hook OpenFunc) iofs.FS {
+	return &hookedFS{fsys: fsys, hook: hook}
+}
+
+// FS returns a handler which serves the contents of fsys, stripping prefix
+// from the request path before looking up the file. This is the fs.FS
+// counterpart of DirHandler: fsys can be an embed.FS, an fstest.MapFS, a
+// zip.Reader, a fs.Sub of a larger tree, or one wrapped with WithOpenHook.
+func FS(fsys iofs.FS, prefix string) http.Handler {
+	return http.StripPrefix(prefix, http.FileServer(http.FS(fsys)))
+}
+
+// DirHandler returns a handler which serves the contents of dir,
+// stripping prefix from the request path before looking up the file.
+// It's a thin wrapper around FS(os.DirFS(dir), prefix), kept for backwards
+// compatibility with callers working against plain OS paths.
+func DirHandler(dir string, prefix string) http.Handler {
+	return FS(os.DirFS(dir), prefix)
+}
+
+// StaticContentHandler returns a handler which always serves the same
+// contents, no matter the request path. cType is used as the Content-Type
+// only when it cannot be resolved from the request's path extension.
+func StaticContentHandler(contents []byte, cType string, opts ...StaticOption) http.Handler {
+	var cfg staticOptions
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		finalType := staticContentType(req.URL.Path, cType)
+		if cfg.DetectContentType {
+			if sniffed, _, err := TypeByContent(bytes.NewReader(contents)); err == nil && sniffed != "" {
+				finalType = sniffed
+			}
+		}
+
+		res.Header().Set("Content-Type", finalType)
+		http.ServeContent(res, req, req.URL.Path, time.Time{}, bytes.NewReader(contents))
+	})
+}
+
+// FaviconFS returns a handler which serves the favicon named name out of
+// fsys, detecting its Content-Type from name's extension.
+func FaviconFS(fsys iofs.FS, name string) http.Handler {
+	contents, err := iofs.ReadFile(fsys, name)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", errFileRead, err.Error())
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+		})
+	}
+
+	cType := staticContentType(name, "")
+	return StaticContentHandler(contents, cType)
+}
+
+// FaviconHandler returns a handler which serves the favicon located at
+// favPath, detecting its Content-Type from the file's extension. It's a
+// thin wrapper around FaviconFS(os.DirFS(dir), name).
+func FaviconHandler(favPath string) http.Handler {
+	dir, name := filepath.Split(favPath)
+	if dir == "" {
+		dir = "."
+	}
+	return FaviconFS(os.DirFS(dir), name)
+}
+
+// SendStaticFS returns a handler which sends the file named name out of
+// fsys to the client as an attachment, so the browser downloads it instead
+// of rendering it inline.
+func SendStaticFS(fsys iofs.FS, name string, opts ...StaticOption) http.Handler {
+	contents, err := iofs.ReadFile(fsys, name)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", errFileRead, err.Error())
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+		})
+	}
+
+	var cfg staticOptions
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	_, filename := filepath.Split(name)
+	cType := staticContentType(name, "")
+	if cfg.DetectContentType {
+		if sniffed, _, err := TypeByContent(bytes.NewReader(contents)); err == nil && sniffed != "" {
+			cType = sniffed
+		}
+	}
+
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set(contentDisposition, "attachment;filename="+filename)
+		res.Header().Set("Content-Type", cType)
+		http.ServeContent(res, req, name, time.Time{}, bytes.NewReader(contents))
+	})
+}
+
+// SendStaticFileHandler returns a handler which sends the file located at
+// path to the client as an attachment, so the browser downloads it instead
+// of rendering it inline. It's a thin wrapper around
+// SendStaticFS(os.DirFS(dir), name).
+func SendStaticFileHandler(path string, opts ...StaticOption) http.Handler {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	return SendStaticFS(os.DirFS(dir), name, opts...)
+}
@@ -2,12 +2,14 @@ package fs
 
 import (
 	"bytes"
+	iofs "io/fs"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestStaticContentHandler(t *testing.T) {
@@ -136,3 +138,139 @@ func TestSendStaticFileHandler(t *testing.T) {
 		t.Errorf("handler returned wrong contents")
 	}
 }
+
+// TestStaticContentHandlerDetectContentType checks that the DetectContentType
+// StaticOption sniffs the served bytes instead of trusting the misleading
+// cType argument.
+func TestStaticContentHandlerDetectContentType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n" + "rest of the file does not matter for sniffing")
+
+	req, err := http.NewRequest("GET", "/image", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := StaticContentHandler(png, "text/plain", DetectContentType(true))
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	if ctype := res.Header().Get("Content-Type"); ctype != "image/png" {
+		t.Errorf("handler returned wrong content type: got %v want %v", ctype, "image/png")
+	}
+
+	if !bytes.Equal(res.Body.Bytes(), png) {
+		t.Error("handler returned wrong contents")
+	}
+}
+
+// TestStaticContentHandlerWithoutDetectContentType checks that, without the
+// option, the misleading cType is trusted as before.
+func TestStaticContentHandlerWithoutDetectContentType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n" + "rest of the file does not matter for sniffing")
+
+	req, err := http.NewRequest("GET", "/image", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := StaticContentHandler(png, "text/plain")
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	if ctype := res.Header().Get("Content-Type"); ctype != "text/plain; charset=utf-8" {
+		t.Errorf("handler returned wrong content type: got %v want %v", ctype, "text/plain; charset=utf-8")
+	}
+}
+
+// TestFSServesMapFS checks that FS works against any fs.FS implementation,
+// not just os.DirFS, and that it still strips the given prefix.
+func TestFSServesMapFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hello.txt": &fstest.MapFile{Data: []byte("hello from MapFS")},
+	}
+
+	req, err := http.NewRequest("GET", "/assets/hello.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := FS(fsys, "/assets/")
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	if body := res.Body.String(); body != "hello from MapFS" {
+		t.Errorf("handler returned wrong contents: got %q", body)
+	}
+}
+
+// TestWithOpenHookDeniesAccess checks that a hook installed via WithOpenHook
+// is consulted on every Open, and can reject a request before it reaches
+// the underlying fs.FS.
+func TestWithOpenHookDeniesAccess(t *testing.T) {
+	fsys := fstest.MapFS{
+		"secret.txt": &fstest.MapFile{Data: []byte("top secret")},
+	}
+
+	guarded := WithOpenHook(fsys, func(fsys iofs.FS, name string) (iofs.File, error) {
+		if name == "secret.txt" {
+			return nil, iofs.ErrPermission
+		}
+		return fsys.Open(name)
+	})
+
+	req, err := http.NewRequest("GET", "/secret.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := FS(guarded, "")
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	if status := res.Code; status == http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v, want non-200 (denied)", status)
+	}
+}
+
+// TestWithOpenHookAllowsAccess checks that a hook which lets a request
+// through still serves it normally.
+func TestWithOpenHookAllowsAccess(t *testing.T) {
+	fsys := fstest.MapFS{
+		"public.txt": &fstest.MapFile{Data: []byte("anyone can read this")},
+	}
+
+	var openedNames []string
+	guarded := WithOpenHook(fsys, func(fsys iofs.FS, name string) (iofs.File, error) {
+		openedNames = append(openedNames, name)
+		return fsys.Open(name)
+	})
+
+	req, err := http.NewRequest("GET", "/public.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := FS(guarded, "")
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	if body := res.Body.String(); body != "anyone can read this" {
+		t.Errorf("handler returned wrong contents: got %q", body)
+	}
+
+	if len(openedNames) == 0 {
+		t.Error("hook was never called")
+	}
+}
@@ -0,0 +1,102 @@
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// sniffLen is the number of bytes TypeByContent/TypeByFile read to
+// determine a file's MIME type from its contents.
+const sniffLen = 3072
+
+// magicSignature matches the leading bytes of a file against a known
+// format and reports the MIME type to use when it matches.
+type magicSignature struct {
+	mimeType string
+	match    func([]byte) bool
+}
+
+func magicPrefix(sig string) func([]byte) bool {
+	return func(b []byte) bool { return bytes.HasPrefix(b, []byte(sig)) }
+}
+
+// magicSignatures is checked in order; the first match wins. It covers the
+// common formats that either have no useful extension (uploads) or that
+// http.DetectContentType doesn't recognize on its own.
+var magicSignatures = []magicSignature{
+	{"image/png", magicPrefix("\x89PNG\r\n\x1a\n")},
+	{"image/jpeg", magicPrefix("\xff\xd8\xff")},
+	{"image/gif", magicPrefix("GIF8")},
+	{"application/pdf", magicPrefix("%PDF-")},
+	{"application/zip", magicPrefix("PK\x03\x04")},
+	{"application/gzip", magicPrefix("\x1f\x8b")},
+	{"application/x-7z-compressed", magicPrefix("7z\xbc\xaf\x27\x1c")},
+	{"image/webp", func(b []byte) bool {
+		return len(b) >= 12 && bytes.HasPrefix(b, []byte("RIFF")) && bytes.Equal(b[8:12], []byte("WEBP"))
+	}},
+	{"video/mp4", func(b []byte) bool {
+		return len(b) >= 8 && bytes.Equal(b[4:8], []byte("ftyp"))
+	}},
+	{"application/ogg", magicPrefix("OggS")},
+	{"audio/mpeg", magicPrefix("ID3")},
+	{"video/x-matroska", magicPrefix("\x1a\x45\xdf\xa3")},
+}
+
+// sniffMagic returns the MIME type of the first magicSignature matching b,
+// or "" if none of them do.
+func sniffMagic(b []byte) string {
+	for _, sig := range magicSignatures {
+		if sig.match(b) {
+			return sig.mimeType
+		}
+	}
+	return ""
+}
+
+// TypeByContent reads up to sniffLen bytes from r and returns the MIME type
+// they match, first against a table of magic-byte signatures and, failing
+// that, against http.DetectContentType. The bytes it read are replayed
+// through rewound, so the caller can keep streaming r's remaining content
+// as if nothing had been read from it.
+func TypeByContent(r io.Reader) (mimeType string, rewound io.Reader, err error) {
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", r, fmt.Errorf("%w: %s", errFileRead, err.Error())
+	}
+	buf = buf[:n]
+	rewound = io.MultiReader(bytes.NewReader(buf), r)
+
+	if t := sniffMagic(buf); t != "" {
+		return t, rewound, nil
+	}
+
+	return http.DetectContentType(buf), rewound, nil
+}
+
+// TypeByFile returns the MIME type of the file at path, detected from its
+// content and, if that only yields the generic "application/octet-stream",
+// from its extension via TypeByExtension.
+func TypeByFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", errFileOpen, err.Error())
+	}
+	defer f.Close()
+
+	t, _, err := TypeByContent(f)
+	if err != nil {
+		return "", err
+	}
+
+	if t == "application/octet-stream" {
+		if ext := TypeByExtension(path); ext != "" {
+			return ext, nil
+		}
+	}
+
+	return t, nil
+}
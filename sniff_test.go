@@ -0,0 +1,96 @@
+package fs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTypeByContent(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\nrest"), "image/png"},
+		{"jpeg", []byte("\xff\xd8\xffrest"), "image/jpeg"},
+		{"gif", []byte("GIF89arest"), "image/gif"},
+		{"pdf", []byte("%PDF-1.4 rest"), "application/pdf"},
+		{"zip", []byte("PK\x03\x04rest"), "application/zip"},
+		{"gzip", []byte("\x1f\x8brest"), "application/gzip"},
+		{"plain text falls back to net/http sniffing", []byte("hello world, plain text"), "text/plain; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rewound, err := TypeByContent(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("TypeByContent returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TypeByContent(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+
+			replayed := make([]byte, len(tt.data))
+			if _, err := rewound.Read(replayed); err != nil {
+				t.Fatalf("reading rewound reader: %v", err)
+			}
+			if !bytes.Equal(replayed, tt.data) {
+				t.Errorf("rewound reader produced %q, want original %q", replayed, tt.data)
+			}
+		})
+	}
+}
+
+func TestTypeByContentRewindsRemainingData(t *testing.T) {
+	data := []byte("PK\x03\x04" + "the rest of the stream")
+
+	_, rewound, err := TypeByContent(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("TypeByContent returned error: %v", err)
+	}
+
+	got := make([]byte, len(data))
+	n, err := rewound.Read(got)
+	if err != nil {
+		t.Fatalf("reading rewound reader: %v", err)
+	}
+	if string(got[:n]) != string(data) {
+		t.Errorf("rewound reader produced %q, want %q", got[:n], data)
+	}
+}
+
+func TestTypeByFile(t *testing.T) {
+	dir := t.TempDir()
+
+	pngPath := filepath.Join(dir, "image.bin")
+	if err := os.WriteFile(pngPath, []byte("\x89PNG\r\n\x1a\nrest"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := TypeByFile(pngPath)
+	if err != nil {
+		t.Fatalf("TypeByFile returned error: %v", err)
+	}
+	if got != "image/png" {
+		t.Errorf("TypeByFile(%q) = %q, want %q", pngPath, got, "image/png")
+	}
+}
+
+func TestTypeByFileFallsBackToExtension(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "data.json")
+	if err := os.WriteFile(path, []byte{0x00, 0x01, 0x02, 0x03}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := TypeByFile(path)
+	if err != nil {
+		t.Fatalf("TypeByFile returned error: %v", err)
+	}
+	if got == "application/octet-stream" {
+		t.Errorf("TypeByFile(%q) did not fall back to the .json extension", path)
+	}
+}